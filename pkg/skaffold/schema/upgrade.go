@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1beta10"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1beta9"
+)
+
+// upgrader is implemented by every schema version that isn't the latest: it
+// hops its config to the next version.
+type upgrader interface {
+	util.VersionedConfig
+	Upgrade() (util.VersionedConfig, error)
+}
+
+// schemaVersions lists the schema versions UpgradeTo can walk, in upgrade
+// order, oldest first; configs of any version not listed here are rejected
+// as unknown. Every version but the last must implement upgrader.
+var schemaVersions = []string{
+	v1beta9.Version,
+	v1beta10.Version,
+	latest.Version,
+}
+
+// JSONPatchOp is a single RFC 6902 JSON Patch operation.
+type JSONPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// UpgradeStep records a single hop made by UpgradeTo: the JSON Patch
+// (RFC 6902) between the pre- and post-upgrade configs, plus any
+// human-readable warnings about behavior changes introduced at that hop
+// that the patch alone wouldn't make clear.
+type UpgradeStep struct {
+	From      string
+	To        string
+	JSONPatch []JSONPatchOp
+	Warnings  []string
+}
+
+// UpgradeError reports that the upgrade chain failed partway through, along
+// with the steps that had already completed before the failure. Callers that
+// want the auditable transformation log even on failure can type-assert for
+// this instead of discarding the error.
+type UpgradeError struct {
+	Cause error
+	Steps []UpgradeStep
+}
+
+func (e *UpgradeError) Error() string {
+	return e.Cause.Error()
+}
+
+func (e *UpgradeError) Unwrap() error {
+	return e.Cause
+}
+
+// UpgradeTo walks the upgrade chain from cfg's APIVersion up to
+// targetAPIVersion, calling Upgrade() at each intermediate version and
+// recording what changed at every hop, which gives an auditable
+// transformation log instead of a single jump straight to the latest
+// version. If a hop fails
+// partway through, the steps completed so far are returned alongside the
+// error via *UpgradeError, rather than being discarded.
+func UpgradeTo(cfg util.VersionedConfig, targetAPIVersion string) (util.VersionedConfig, []UpgradeStep, error) {
+	return upgradeTo(cfg, targetAPIVersion, schemaVersions)
+}
+
+func upgradeTo(cfg util.VersionedConfig, targetAPIVersion string, versions []string) (util.VersionedConfig, []UpgradeStep, error) {
+	startIdx := indexOf(versions, cfg.GetVersion())
+	if startIdx == -1 {
+		return nil, nil, fmt.Errorf("unknown schema version %q", cfg.GetVersion())
+	}
+	targetIdx := indexOf(versions, targetAPIVersion)
+	if targetIdx == -1 {
+		return nil, nil, fmt.Errorf("unknown target schema version %q", targetAPIVersion)
+	}
+	if targetIdx < startIdx {
+		return nil, nil, fmt.Errorf("can't upgrade from %q to older version %q", cfg.GetVersion(), targetAPIVersion)
+	}
+
+	var steps []UpgradeStep
+	current := cfg
+
+	for i := startIdx; i < targetIdx; i++ {
+		up, ok := current.(upgrader)
+		if !ok {
+			return nil, nil, &UpgradeError{
+				Cause: fmt.Errorf("%q can't be upgraded any further", current.GetVersion()),
+				Steps: steps,
+			}
+		}
+
+		before, err := json.Marshal(current)
+		if err != nil {
+			return nil, nil, &UpgradeError{Cause: err, Steps: steps}
+		}
+
+		next, err := up.Upgrade()
+		if err != nil {
+			return nil, nil, &UpgradeError{
+				Cause: fmt.Errorf("upgrading from %s to %s: %w", versions[i], versions[i+1], err),
+				Steps: steps,
+			}
+		}
+
+		after, err := json.Marshal(next)
+		if err != nil {
+			return nil, nil, &UpgradeError{Cause: err, Steps: steps}
+		}
+
+		patch, err := diffJSON(before, after)
+		if err != nil {
+			return nil, nil, &UpgradeError{
+				Cause: fmt.Errorf("diffing %s against %s: %w", versions[i], versions[i+1], err),
+				Steps: steps,
+			}
+		}
+
+		steps = append(steps, UpgradeStep{
+			From:      versions[i],
+			To:        versions[i+1],
+			JSONPatch: patch,
+			Warnings:  warningsFor(versions[i], versions[i+1]),
+		})
+
+		current = next
+	}
+
+	return current, steps, nil
+}
+
+func indexOf(versions []string, apiVersion string) int {
+	for i, v := range versions {
+		if v == apiVersion {
+			return i
+		}
+	}
+	return -1
+}
+
+// warningsFor surfaces notable, non-obvious behavior changes introduced at a
+// given hop that a JSON patch alone wouldn't make clear.
+func warningsFor(from, to string) []string {
+	if from == v1beta9.Version && to == v1beta10.Version {
+		return []string{"build.tagPolicy.gitCommit gained the `TreeSha` and `AbbrevTreeSha` variants"}
+	}
+	return nil
+}
+
+// diffJSON computes an RFC 6902 JSON Patch turning before into after. Arrays
+// are compared as whole values (replaced wholesale rather than diffed
+// element-by-element), which is sufficient for the config-shaped documents
+// UpgradeTo deals with.
+func diffJSON(before, after []byte) ([]JSONPatchOp, error) {
+	var b, a interface{}
+	if err := json.Unmarshal(before, &b); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &a); err != nil {
+		return nil, err
+	}
+
+	var ops []JSONPatchOp
+	diffValue("", b, a, &ops)
+	return ops, nil
+}
+
+func diffValue(path string, before, after interface{}, ops *[]JSONPatchOp) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+
+	if !beforeIsMap || !afterIsMap {
+		if !jsonEqual(before, after) {
+			*ops = append(*ops, JSONPatchOp{Op: "replace", Path: path, Value: after})
+		}
+		return
+	}
+
+	var added, changed, removed []string
+	for k := range afterMap {
+		if _, ok := beforeMap[k]; ok {
+			changed = append(changed, k)
+		} else {
+			added = append(added, k)
+		}
+	}
+	for k := range beforeMap {
+		if _, ok := afterMap[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	// Keys are escaped as RFC 6901 JSON Pointer reference tokens.
+	escape := strings.NewReplacer("~", "~0", "/", "~1").Replace
+
+	for _, k := range changed {
+		diffValue(path+"/"+escape(k), beforeMap[k], afterMap[k], ops)
+	}
+	for _, k := range added {
+		*ops = append(*ops, JSONPatchOp{Op: "add", Path: path + "/" + escape(k), Value: afterMap[k]})
+	}
+	for _, k := range removed {
+		*ops = append(*ops, JSONPatchOp{Op: "remove", Path: path + "/" + escape(k)})
+	}
+}
+
+func jsonEqual(a, b interface{}) bool {
+	aj, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bj, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(aj) == string(bj)
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1beta10"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1beta9"
+)
+
+// fakeConfig is a minimal util.VersionedConfig used to exercise the
+// version-walking logic in upgradeTo without depending on any real schema
+// package.
+type fakeConfig struct {
+	APIVersion string `json:"apiVersion"`
+	Value      string `json:"value"`
+}
+
+func (f *fakeConfig) GetVersion() string { return f.APIVersion }
+
+// fakeUpgradeableConfig can additionally hop to the next version. chain
+// lists the versions it still hops through after itself; when only one
+// remains, Upgrade returns a plain (terminal) fakeConfig instead of another
+// fakeUpgradeableConfig.
+type fakeUpgradeableConfig struct {
+	fakeConfig
+	chain      []string
+	upgradeErr error
+}
+
+func (f *fakeUpgradeableConfig) Upgrade() (util.VersionedConfig, error) {
+	if f.upgradeErr != nil {
+		return nil, f.upgradeErr
+	}
+
+	next := fakeConfig{APIVersion: f.chain[0], Value: f.Value + "!"}
+	if len(f.chain) == 1 {
+		return &next, nil
+	}
+	return &fakeUpgradeableConfig{fakeConfig: next, chain: f.chain[1:]}, nil
+}
+
+var fakeVersions = []string{"v1", "v2", "v3"}
+
+func TestUpgradeToIndexOf(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected int
+	}{
+		{"v1", 0},
+		{"v2", 1},
+		{"v3", 2},
+		{"unknown", -1},
+	}
+
+	for _, test := range tests {
+		if actual := indexOf(fakeVersions, test.version); actual != test.expected {
+			t.Errorf("indexOf(%q) = %d, expected %d", test.version, actual, test.expected)
+		}
+	}
+}
+
+func TestUpgradeToMultiHop(t *testing.T) {
+	// v1 hops to v2, then v2 hops to v3, so a full v1->v3 walk succeeds with
+	// two recorded steps.
+	cfg := &fakeUpgradeableConfig{
+		fakeConfig: fakeConfig{APIVersion: "v1", Value: "a"},
+		chain:      []string{"v2", "v3"},
+	}
+
+	final, steps, err := upgradeTo(cfg, "v3", fakeVersions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 completed hops, got %d", len(steps))
+	}
+	if steps[0].From != "v1" || steps[0].To != "v2" {
+		t.Errorf("unexpected first step: %+v", steps[0])
+	}
+	if steps[1].From != "v2" || steps[1].To != "v3" {
+		t.Errorf("unexpected second step: %+v", steps[1])
+	}
+
+	final2 := final.(*fakeConfig)
+	if final2.APIVersion != "v3" {
+		t.Errorf("expected to reach v3, got %q", final2.APIVersion)
+	}
+}
+
+func TestUpgradeToSameVersion(t *testing.T) {
+	cfg := &fakeConfig{APIVersion: "v2", Value: "a"}
+
+	final, steps, err := upgradeTo(cfg, "v2", fakeVersions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(steps) != 0 {
+		t.Errorf("expected no hops, got %d", len(steps))
+	}
+	if final != cfg {
+		t.Errorf("expected the same config back unchanged")
+	}
+}
+
+func TestUpgradeToOlderVersion(t *testing.T) {
+	cfg := &fakeConfig{APIVersion: "v3"}
+
+	if _, _, err := upgradeTo(cfg, "v1", fakeVersions); err == nil {
+		t.Fatal("expected an error upgrading to an older version")
+	}
+}
+
+func TestUpgradeToUnknownVersions(t *testing.T) {
+	if _, _, err := upgradeTo(&fakeConfig{APIVersion: "nope"}, "v2", fakeVersions); err == nil {
+		t.Fatal("expected an error for an unknown starting version")
+	}
+	if _, _, err := upgradeTo(&fakeConfig{APIVersion: "v1"}, "nope", fakeVersions); err == nil {
+		t.Fatal("expected an error for an unknown target version")
+	}
+}
+
+func TestUpgradeToCantUpgradeFurther(t *testing.T) {
+	cfg := &fakeConfig{APIVersion: "v1"}
+
+	_, _, err := upgradeTo(cfg, "v2", fakeVersions)
+	if err == nil {
+		t.Fatal("expected an error since fakeConfig has no Upgrade method")
+	}
+	var upgradeErr *UpgradeError
+	if !errors.As(err, &upgradeErr) {
+		t.Fatalf("expected *UpgradeError, got %T", err)
+	}
+	if len(upgradeErr.Steps) != 0 {
+		t.Errorf("expected no completed steps, got %d", len(upgradeErr.Steps))
+	}
+}
+
+func TestUpgradeToPartialStepsOnFailure(t *testing.T) {
+	// v1tov2 hops successfully to a plain fakeConfig that can't hop any
+	// further, so the second requested hop fails. The first hop's step must
+	// survive on the returned error.
+	v1tov2 := &fakeUpgradeableConfig{
+		fakeConfig: fakeConfig{APIVersion: "v1", Value: "a"},
+		chain:      []string{"v2"},
+	}
+
+	versions := []string{"v1", "v2", "v3", "v4"}
+
+	_, steps, err := upgradeTo(v1tov2, "v3", versions)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	var upgradeErr *UpgradeError
+	if !errors.As(err, &upgradeErr) {
+		t.Fatalf("expected *UpgradeError, got %T", err)
+	}
+	if len(upgradeErr.Steps) != 1 {
+		t.Fatalf("expected 1 completed step to survive the failure, got %d", len(upgradeErr.Steps))
+	}
+	if upgradeErr.Steps[0].From != "v1" || upgradeErr.Steps[0].To != "v2" {
+		t.Errorf("unexpected step: %+v", upgradeErr.Steps[0])
+	}
+	if steps != nil {
+		t.Errorf("expected the direct return value to be nil, the partial log lives on the error")
+	}
+}
+
+func TestUpgradeToUpgradeError(t *testing.T) {
+	cfg := &fakeUpgradeableConfig{
+		fakeConfig: fakeConfig{APIVersion: "v1"},
+		upgradeErr: errors.New("boom"),
+	}
+
+	_, _, err := upgradeTo(cfg, "v2", fakeVersions)
+
+	var upgradeErr *UpgradeError
+	if !errors.As(err, &upgradeErr) {
+		t.Fatalf("expected *UpgradeError, got %T", err)
+	}
+	if len(upgradeErr.Steps) != 0 {
+		t.Errorf("expected no completed steps, got %d", len(upgradeErr.Steps))
+	}
+}
+
+func TestWarningsFor(t *testing.T) {
+	tests := []struct {
+		from, to string
+		expected int
+	}{
+		{v1beta9.Version, v1beta10.Version, 1},
+		{"v1", "v2", 0},
+	}
+
+	for _, test := range tests {
+		if actual := len(warningsFor(test.from, test.to)); actual != test.expected {
+			t.Errorf("warningsFor(%q, %q) returned %d warnings, expected %d", test.from, test.to, actual, test.expected)
+		}
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		description string
+		before      string
+		after       string
+		expected    []JSONPatchOp
+	}{
+		{
+			description: "no change",
+			before:      `{"a":1}`,
+			after:       `{"a":1}`,
+			expected:    nil,
+		},
+		{
+			description: "field changed",
+			before:      `{"a":1}`,
+			after:       `{"a":2}`,
+			expected:    []JSONPatchOp{{Op: "replace", Path: "/a", Value: float64(2)}},
+		},
+		{
+			description: "field added",
+			before:      `{}`,
+			after:       `{"a":1}`,
+			expected:    []JSONPatchOp{{Op: "add", Path: "/a", Value: float64(1)}},
+		},
+		{
+			description: "field removed",
+			before:      `{"a":1}`,
+			after:       `{}`,
+			expected:    []JSONPatchOp{{Op: "remove", Path: "/a"}},
+		},
+		{
+			description: "nested field changed",
+			before:      `{"a":{"b":1}}`,
+			after:       `{"a":{"b":2}}`,
+			expected:    []JSONPatchOp{{Op: "replace", Path: "/a/b", Value: float64(2)}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			patch, err := diffJSON([]byte(test.before), []byte(test.after))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(patch) != len(test.expected) {
+				t.Fatalf("got %+v, expected %+v", patch, test.expected)
+			}
+			for i := range patch {
+				if patch[i] != test.expected[i] {
+					t.Errorf("op %d: got %+v, expected %+v", i, patch[i], test.expected[i])
+				}
+			}
+		})
+	}
+}
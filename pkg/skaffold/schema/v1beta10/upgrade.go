@@ -17,8 +17,12 @@ limitations under the License.
 package v1beta10
 
 import (
+	"fmt"
+	"strings"
+
 	next "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/util"
+	previous "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1beta9"
 	pkgutil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
 )
 
@@ -36,3 +40,47 @@ func (config *SkaffoldConfig) Upgrade() (util.VersionedConfig, error) {
 
 	return &newConfig, err
 }
+
+// DowngradeError is returned by Downgrade when representing a config with
+// an older schema version requires dropping, or lossily converting, fields
+// that don't exist in that version.
+type DowngradeError struct {
+	Version string
+	Dropped []string
+}
+
+func (e *DowngradeError) Error() string {
+	return fmt.Sprintf("downgrading to %s is lossy: %s", e.Version, strings.Join(e.Dropped, "; "))
+}
+
+// Downgrade downgrades a configuration to the previous version.
+// Config changes from v1beta10 to v1beta9
+// 1. Removals:
+//    - GitTagger variants `TreeSha` and `AbbrevTreeSha` don't exist in
+//      v1beta9. They're downgraded to the closest equivalent, `CommitSha`,
+//      and reported through the returned DowngradeError.
+// 2. No additions
+// 3. No Updates
+func (config *SkaffoldConfig) Downgrade() (util.VersionedConfig, error) {
+	var previousConfig previous.SkaffoldConfig
+
+	if err := pkgutil.CloneThroughJSON(config, &previousConfig); err != nil {
+		return nil, err
+	}
+	previousConfig.APIVersion = previous.Version
+
+	var dropped []string
+	if gitTagger := config.Build.TagPolicy.GitTagger; gitTagger != nil {
+		switch gitTagger.Variant {
+		case "TreeSha", "AbbrevTreeSha":
+			dropped = append(dropped, fmt.Sprintf("build.tagPolicy.gitCommit.variant %q downgraded to %q", gitTagger.Variant, "CommitSha"))
+			previousConfig.Build.TagPolicy.GitTagger.Variant = "CommitSha"
+		}
+	}
+
+	if len(dropped) > 0 {
+		return &previousConfig, &DowngradeError{Version: previous.Version, Dropped: dropped}
+	}
+
+	return &previousConfig, nil
+}
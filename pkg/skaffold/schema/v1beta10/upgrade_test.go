@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta10
+
+import (
+	"testing"
+
+	previous "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1beta9"
+)
+
+func TestDowngrade(t *testing.T) {
+	tests := []struct {
+		description     string
+		gitTagger       *GitTagger
+		shouldErr       bool
+		expectedVariant string
+	}{
+		{
+			description: "no git tagger",
+		},
+		{
+			description:     "variant other than TreeSha/AbbrevTreeSha round-trips untouched",
+			gitTagger:       &GitTagger{Variant: "CommitSha"},
+			expectedVariant: "CommitSha",
+		},
+		{
+			description:     "TreeSha is downgraded to CommitSha",
+			gitTagger:       &GitTagger{Variant: "TreeSha"},
+			shouldErr:       true,
+			expectedVariant: "CommitSha",
+		},
+		{
+			description:     "AbbrevTreeSha is downgraded to CommitSha",
+			gitTagger:       &GitTagger{Variant: "AbbrevTreeSha"},
+			shouldErr:       true,
+			expectedVariant: "CommitSha",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			config := &SkaffoldConfig{
+				APIVersion: Version,
+				Build: BuildConfig{
+					TagPolicy: TagPolicy{
+						GitTagger: test.gitTagger,
+					},
+				},
+			}
+
+			downgraded, err := config.Downgrade()
+
+			if test.shouldErr {
+				downgradeErr, ok := err.(*DowngradeError)
+				if !ok {
+					t.Fatalf("expected a *DowngradeError, got %T (%v)", err, err)
+				}
+				if len(downgradeErr.Dropped) == 0 {
+					t.Errorf("expected DowngradeError.Dropped to be populated")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			previousConfig, ok := downgraded.(*previous.SkaffoldConfig)
+			if !ok {
+				t.Fatalf("expected *previous.SkaffoldConfig, got %T", downgraded)
+			}
+			if previousConfig.APIVersion != previous.Version {
+				t.Errorf("expected APIVersion %q, got %q", previous.Version, previousConfig.APIVersion)
+			}
+
+			if test.gitTagger != nil {
+				if previousConfig.Build.TagPolicy.GitTagger.Variant != test.expectedVariant {
+					t.Errorf("expected variant %q, got %q", test.expectedVariant, previousConfig.Build.TagPolicy.GitTagger.Variant)
+				}
+			}
+		})
+	}
+}